@@ -0,0 +1,73 @@
+package audio
+
+import (
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+)
+
+// FLACSource adapts a *flac.Stream into a SampleSource, downmixing every
+// subframe (channel) into a single mono stream by averaging instead of
+// reading only the first channel.
+type FLACSource struct {
+	stream  *flac.Stream
+	pending []float64
+}
+
+// NewFLACSource wraps stream as a mono SampleSource at its native rate.
+func NewFLACSource(stream *flac.Stream) *FLACSource {
+	return &FLACSource{stream: stream}
+}
+
+// SampleRate returns the stream's native sample rate.
+func (s *FLACSource) SampleRate() uint32 {
+	return s.stream.Info.SampleRate
+}
+
+// Read fills buf with downmixed mono samples, pulling further FLAC frames
+// from the underlying stream as needed.
+func (s *FLACSource) Read(buf []float64) (int, error) {
+	n := 0
+
+	for n < len(buf) {
+		if len(s.pending) == 0 {
+			fr, err := s.stream.ParseNext()
+			if err != nil {
+				if n > 0 {
+					return n, nil
+				}
+				return 0, err
+			}
+
+			s.pending = downmixFrame(fr)
+		}
+
+		copied := copy(buf[n:], s.pending)
+		n += copied
+		s.pending = s.pending[copied:]
+	}
+
+	return n, nil
+}
+
+// downmixFrame averages every subframe (channel) of f into mono samples,
+// scaled into [-1, 1] by the frame's bit depth. A single-channel frame
+// downmixes to exactly its one channel.
+func downmixFrame(f *frame.Frame) []float64 {
+	channels := len(f.Subframes)
+	nSamples := f.Subframes[0].NSamples
+	denominator := float64(int64(1) << f.BitsPerSample)
+
+	samples := make([]float64, nSamples)
+
+	for i := 0; i < nSamples; i++ {
+		var sum float64
+
+		for _, subframe := range f.Subframes {
+			sum += float64(subframe.Samples[i])
+		}
+
+		samples[i] = sum / float64(channels) / denominator
+	}
+
+	return samples
+}
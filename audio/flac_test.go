@@ -0,0 +1,55 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/mewkiz/flac/frame"
+)
+
+func TestDownmixFrameAveragesChannels(t *testing.T) {
+	f := &frame.Frame{
+		Header: frame.Header{BitsPerSample: 16},
+		Subframes: []*frame.Subframe{
+			{NSamples: 2, Samples: []int32{10000, -10000}},
+			{NSamples: 2, Samples: []int32{20000, -20000}},
+		},
+	}
+
+	got := downmixFrame(f)
+
+	want := []float64{
+		(10000 + 20000) / 2 / 65536.0,
+		(-10000 - 20000) / 2 / 65536.0,
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("len(downmixFrame(f)) = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("downmixFrame(f)[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDownmixFrameSingleChannelIsUnchanged(t *testing.T) {
+	f := &frame.Frame{
+		Header: frame.Header{BitsPerSample: 8},
+		Subframes: []*frame.Subframe{
+			{NSamples: 3, Samples: []int32{10, -64, 127}},
+		},
+	}
+
+	got := downmixFrame(f)
+
+	want := []float64{10.0 / 256, -64.0 / 256, 127.0 / 256}
+
+	if len(got) != len(want) {
+		t.Fatalf("len(downmixFrame(f)) = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("downmixFrame(f)[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
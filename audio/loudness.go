@@ -0,0 +1,220 @@
+package audio
+
+import "math"
+
+const (
+	// ReferenceLoudnessLUFS is the ReplayGain 2.0 / EBU R128 reference
+	// loudness that tracks are normalized towards.
+	ReferenceLoudnessLUFS = -18.0
+
+	// AbsoluteGateLUFS discards silent blocks before the loudness mean is
+	// computed, per ITU-R BS.1770.
+	AbsoluteGateLUFS = -70.0
+
+	// RelativeGateLU is applied below the absolute-gated mean to discard
+	// further quiet blocks (the "relative gate").
+	RelativeGateLU = -10.0
+
+	// BlockSeconds and BlockHopSeconds set the 400 ms, 75%-overlapping
+	// gating blocks BS.1770 measures loudness over.
+	BlockSeconds    = 0.4
+	BlockHopSeconds = 0.1
+
+	// truePeakOversample is the oversampling factor ITU-R BS.1770 Annex 2 /
+	// EBU R128 use to measure true (inter-sample) peak: the largest value a
+	// D/A reconstruction of the signal would actually reach, which can
+	// exceed the largest sample value itself.
+	truePeakOversample = 4
+)
+
+// kWeight applies the ITU-R BS.1770 K-weighting pre-filter (a high-shelf
+// stage followed by a high-pass stage) used to approximate human loudness
+// perception before measuring block power.
+func kWeight(samples []float64, sampleRate uint32) []float64 {
+	shelf, highPass := newKWeightingFilters(sampleRate)
+
+	out := make([]float64, len(samples))
+	for i, s := range samples {
+		out[i] = highPass.process(shelf.process(s))
+	}
+
+	return out
+}
+
+// newKWeightingFilters derives the BS.1770 K-weighting biquad coefficients
+// for sampleRate by applying the bilinear transform to the standard's analog
+// shelf/high-pass prototypes, so the filter stays correct at rates other
+// than the reference 48kHz.
+func newKWeightingFilters(sampleRate uint32) (shelf, highPass biquad) {
+	fs := float64(sampleRate)
+
+	const (
+		shelfF0 = 1681.974450955533
+		shelfG  = 3.999843853973347
+		shelfQ  = 0.7071752369554196
+	)
+
+	k := math.Tan(math.Pi * shelfF0 / fs)
+	vh := math.Pow(10, shelfG/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+
+	shelf = biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	const (
+		highPassF0 = 38.13547087602444
+		highPassQ  = 0.5003270373238773
+	)
+
+	k = math.Tan(math.Pi * highPassF0 / fs)
+	a0 = 1 + k/highPassQ + k*k
+
+	highPass = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/highPassQ + k*k) / a0,
+	}
+
+	return shelf, highPass
+}
+
+// biquad is a direct-form-I IIR filter section.
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+
+	x1, x2, y1, y2 float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+
+	return y
+}
+
+// MeasureLoudness computes the integrated loudness (LUFS) and true peak of
+// samples following the BS.1770/EBU R128 gated-block algorithm: K-weight,
+// split into 400 ms blocks with 75% overlap, discard blocks below the
+// absolute gate, then discard blocks below a further relative gate set from
+// the mean of what remains.
+func MeasureLoudness(samples []float64, sampleRate uint32) (lufs, peak float64) {
+	weighted := kWeight(samples, sampleRate)
+	peak = truePeak(samples, sampleRate)
+
+	blockSize := int(BlockSeconds * float64(sampleRate))
+	hop := int(BlockHopSeconds * float64(sampleRate))
+	if blockSize < 1 || hop < 1 || len(weighted) < blockSize {
+		return -math.MaxFloat64, peak
+	}
+
+	var powers []float64
+	for start := 0; start+blockSize <= len(weighted); start += hop {
+		powers = append(powers, meanSquare(weighted[start:start+blockSize]))
+	}
+
+	gated := gateByLoudness(powers, AbsoluteGateLUFS)
+	if len(gated) == 0 {
+		return -math.MaxFloat64, peak
+	}
+
+	relativeThreshold := powerToLUFS(mean(gated)) + RelativeGateLU
+	gated = gateByLoudness(gated, relativeThreshold)
+	if len(gated) == 0 {
+		return -math.MaxFloat64, peak
+	}
+
+	return powerToLUFS(mean(gated)), peak
+}
+
+// truePeak oversamples samples by truePeakOversample using the same
+// windowed-sinc polyphase filter Resample uses, then returns the largest
+// absolute value the oversampled signal reaches, catching inter-sample
+// peaks that a plain max-over-samples would miss.
+func truePeak(samples []float64, sampleRate uint32) float64 {
+	oversampled := Resample(samples, sampleRate, sampleRate*truePeakOversample)
+
+	var peak float64
+	for _, s := range oversampled {
+		if abs := math.Abs(s); abs > peak {
+			peak = abs
+		}
+	}
+
+	return peak
+}
+
+func gateByLoudness(powers []float64, thresholdLUFS float64) []float64 {
+	var kept []float64
+	for _, p := range powers {
+		if powerToLUFS(p) > thresholdLUFS {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func powerToLUFS(power float64) float64 {
+	if power <= 0 {
+		return -math.MaxFloat64
+	}
+	return -0.691 + 10*math.Log10(power)
+}
+
+func meanSquare(samples []float64) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += s * s
+	}
+	return sum / float64(len(samples))
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// GainForLoudness returns the gain, in dB, needed to bring a signal measured
+// at lufs to ReferenceLoudnessLUFS.
+func GainForLoudness(lufs float64) float64 {
+	if lufs == -math.MaxFloat64 {
+		return 0
+	}
+	return ReferenceLoudnessLUFS - lufs
+}
+
+// ApplyGain returns samples scaled by gainDB.
+func ApplyGain(samples []float64, gainDB float64) []float64 {
+	factor := math.Pow(10, gainDB/20)
+	out := make([]float64, len(samples))
+
+	for i, s := range samples {
+		out[i] = s * factor
+	}
+
+	return out
+}
+
+// Normalize measures samples' integrated loudness and true peak, then
+// returns a gain-corrected copy alongside the measurements so callers (e.g.
+// a fingerprint store) can record them, mirroring the replayGain/replayPeak
+// fields of adjacent ecosystem tools.
+func Normalize(samples []float64, sampleRate uint32) (normalized []float64, gainDB, peak float64) {
+	lufs, peak := MeasureLoudness(samples, sampleRate)
+	gainDB = GainForLoudness(lufs)
+
+	return ApplyGain(samples, gainDB), gainDB, peak
+}
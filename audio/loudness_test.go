@@ -0,0 +1,59 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// fullScaleSineLUFS is the well-known integrated loudness of a 997Hz,
+// full-scale sine wave under BS.1770 K-weighting: -3.01 LUFS (ReplayGain's
+// own reference-tone figure).
+const fullScaleSineLUFS = -3.01
+
+func TestMeasureLoudnessFullScaleSine(t *testing.T) {
+	const sampleRate = 48000
+
+	samples := make([]float64, 5*sampleRate)
+	for i := range samples {
+		samples[i] = sineAt(997, sampleRate, i)
+	}
+
+	lufs, peak := MeasureLoudness(samples, sampleRate)
+
+	if diff := lufs - fullScaleSineLUFS; diff > 0.5 || diff < -0.5 {
+		t.Errorf("lufs = %v, want approximately %v", lufs, fullScaleSineLUFS)
+	}
+	if diff := peak - 1.0; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("peak = %v, want 1", peak)
+	}
+}
+
+func TestMeasureLoudnessMatchesAcrossSampleRates(t *testing.T) {
+	rates := []uint32{44100, 48000, 16000}
+
+	for _, rate := range rates {
+		samples := make([]float64, 5*int(rate))
+		for i := range samples {
+			samples[i] = sineAt(997, int(rate), i)
+		}
+
+		lufs, _ := MeasureLoudness(samples, rate)
+
+		if diff := lufs - fullScaleSineLUFS; diff > 0.5 || diff < -0.5 {
+			t.Errorf("rate %d: lufs = %v, want approximately %v", rate, lufs, fullScaleSineLUFS)
+		}
+	}
+}
+
+func TestMeasureLoudnessSilenceIsUnmeasurable(t *testing.T) {
+	samples := make([]float64, 5*48000)
+
+	lufs, peak := MeasureLoudness(samples, 48000)
+
+	if lufs != -math.MaxFloat64 {
+		t.Errorf("lufs = %v, want -MaxFloat64 for silence", lufs)
+	}
+	if peak != 0 {
+		t.Errorf("peak = %v, want 0 for silence", peak)
+	}
+}
@@ -0,0 +1,204 @@
+package audio
+
+import (
+	"io"
+	"math"
+)
+
+const (
+	// phases is the number of precomputed sub-sample filter phases in the
+	// polyphase kernel.
+	phases = 256
+
+	// halfTaps is the number of filter taps on each side of a phase's
+	// center; a wider kernel trades latency for stop-band rejection.
+	halfTaps = 16
+)
+
+// polyphaseKernel is a bank of windowed-sinc FIR filters, one per fractional
+// sample phase, sized so every phase sums to unity gain (preserving signal
+// level) while attenuating content above the target Nyquist frequency.
+type polyphaseKernel [][]float64
+
+// newPolyphaseKernel builds a kernel whose cutoff is set for resampling
+// between sourceRate and targetRate: when downsampling, the cutoff is
+// lowered proportionally to avoid aliasing.
+func newPolyphaseKernel(sourceRate, targetRate uint32) polyphaseKernel {
+	cutoff := 0.5
+	if targetRate < sourceRate {
+		cutoff *= float64(targetRate) / float64(sourceRate)
+	}
+
+	width := 2*halfTaps + 1
+	kernel := make(polyphaseKernel, phases)
+
+	for p := 0; p < phases; p++ {
+		frac := float64(p) / float64(phases)
+		taps := make([]float64, width)
+		var sum float64
+
+		for t := -halfTaps; t <= halfTaps; t++ {
+			x := float64(t) - frac
+			value := sinc(2*cutoff*x) * 2 * cutoff * blackmanHarris(t, halfTaps)
+			taps[t+halfTaps] = value
+			sum += value
+		}
+
+		if sum != 0 {
+			for i := range taps {
+				taps[i] /= sum
+			}
+		}
+
+		kernel[p] = taps
+	}
+
+	return kernel
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackmanHarris evaluates a four-term Blackman-Harris window at tap offset
+// t within a kernel spanning [-half, half].
+func blackmanHarris(t, half int) float64 {
+	const a0, a1, a2, a3 = 0.35875, 0.48829, 0.14128, 0.01168
+
+	x := float64(t+half) / float64(2*half)
+
+	return a0 - a1*math.Cos(2*math.Pi*x) + a2*math.Cos(4*math.Pi*x) - a3*math.Cos(6*math.Pi*x)
+}
+
+// Resample band-limits and resamples an already-buffered mono signal from
+// sourceRate to targetRate using a windowed-sinc polyphase filter.
+func Resample(samples []float64, sourceRate, targetRate uint32) []float64 {
+	if sourceRate == targetRate || len(samples) == 0 {
+		return samples
+	}
+
+	kernel := newPolyphaseKernel(sourceRate, targetRate)
+	ratio := float64(targetRate) / float64(sourceRate)
+	outLen := int(float64(len(samples)) * ratio)
+	out := make([]float64, outLen)
+
+	for i := range out {
+		out[i] = kernel.at(samples, float64(i)/ratio)
+	}
+
+	return out
+}
+
+func (k polyphaseKernel) at(samples []float64, srcPos float64) float64 {
+	srcIndex := int(math.Floor(srcPos))
+	frac := srcPos - float64(srcIndex)
+	taps := k[int(frac*phases)]
+
+	var sum float64
+
+	for t := -halfTaps; t <= halfTaps; t++ {
+		idx := srcIndex + t
+		if idx < 0 || idx >= len(samples) {
+			continue
+		}
+
+		sum += samples[idx] * taps[t+halfTaps]
+	}
+
+	return sum
+}
+
+// ResampleSource wraps a SampleSource, exposing its samples band-limited and
+// resampled to targetRate. It buffers enough trailing input to give every
+// output sample a full kernel window before emitting it, and carries the
+// unconsumed tail forward across Read calls.
+type ResampleSource struct {
+	source     SampleSource
+	targetRate uint32
+	kernel     polyphaseKernel
+	ratio      float64
+
+	buf    []float64
+	eof    bool
+	srcPos float64 // position of the next output sample, in source-sample units
+}
+
+// NewResampleSource wraps source, converting it to targetRate.
+func NewResampleSource(source SampleSource, targetRate uint32) *ResampleSource {
+	sourceRate := source.SampleRate()
+
+	return &ResampleSource{
+		source:     source,
+		targetRate: targetRate,
+		kernel:     newPolyphaseKernel(sourceRate, targetRate),
+		ratio:      float64(targetRate) / float64(sourceRate),
+	}
+}
+
+// SampleRate returns the rate samples are resampled to.
+func (r *ResampleSource) SampleRate() uint32 {
+	return r.targetRate
+}
+
+// Read fills buf with resampled mono samples.
+func (r *ResampleSource) Read(buf []float64) (int, error) {
+	if r.ratio == 1 {
+		return r.source.Read(buf)
+	}
+
+	n := 0
+
+	for n < len(buf) {
+		if !r.fill(int(r.srcPos) + halfTaps + 1) {
+			break
+		}
+
+		out := r.kernel.at(r.buf, r.srcPos)
+		buf[n] = out
+		n++
+		r.srcPos += 1 / r.ratio
+	}
+
+	r.discardConsumed()
+
+	if n == 0 && r.eof {
+		return 0, io.EOF
+	}
+
+	return n, nil
+}
+
+// fill ensures r.buf has at least needed samples buffered, reading further
+// from the source unless it has already hit EOF.
+func (r *ResampleSource) fill(needed int) bool {
+	for len(r.buf) < needed && !r.eof {
+		chunk := make([]float64, 4096)
+		n, err := r.source.Read(chunk)
+		r.buf = append(r.buf, chunk[:n]...)
+
+		if err != nil {
+			r.eof = true
+		}
+	}
+
+	return len(r.buf) >= needed
+}
+
+// discardConsumed drops samples from the front of r.buf that no longer fall
+// within any future output window, adjusting srcPos to match.
+func (r *ResampleSource) discardConsumed() {
+	drop := int(r.srcPos) - halfTaps
+	if drop <= 0 {
+		return
+	}
+	if drop > len(r.buf) {
+		drop = len(r.buf)
+	}
+
+	r.buf = r.buf[drop:]
+	r.srcPos -= float64(drop)
+}
@@ -0,0 +1,72 @@
+package audio
+
+import "math"
+
+// runningTimeConstantSeconds sets how quickly the running loudness estimate
+// tracks the signal; 3s matches the integration time of typical "momentary"
+// loudness meters.
+const runningTimeConstantSeconds = 3.0
+
+// RunningNormalizer gain-corrects a sample stream on the fly using a
+// leaky-integrator loudness estimate, for pipelines that process audio
+// sample-by-sample rather than buffering a whole track for a two-pass
+// measurement.
+type RunningNormalizer struct {
+	shelf, highPass biquad
+	alpha           float64
+	runningPower    float64
+	peak            float64
+	samplesSeen     uint64
+	warmupSamples   uint64
+}
+
+// NewRunningNormalizer creates a normalizer for a stream sampled at
+// sampleRate.
+func NewRunningNormalizer(sampleRate uint32) *RunningNormalizer {
+	alpha := 1 - math.Exp(-1/(runningTimeConstantSeconds*float64(sampleRate)))
+	shelf, highPass := newKWeightingFilters(sampleRate)
+	warmupSamples := uint64(math.Round(runningTimeConstantSeconds * float64(sampleRate)))
+
+	return &RunningNormalizer{alpha: alpha, shelf: shelf, highPass: highPass, warmupSamples: warmupSamples}
+}
+
+// Process K-weights sample into the running loudness estimate, updates the
+// running peak, and returns sample scaled by the gain that estimate implies.
+func (r *RunningNormalizer) Process(sample float64) float64 {
+	weighted := r.highPass.process(r.shelf.process(sample))
+	r.runningPower += r.alpha * (weighted*weighted - r.runningPower)
+	r.samplesSeen++
+
+	if abs := math.Abs(sample); abs > r.peak {
+		r.peak = abs
+	}
+
+	factor := math.Pow(10, r.GainDB()/20)
+
+	return sample * factor
+}
+
+// GainDB returns the gain, in dB, the current running loudness estimate
+// implies. Until the integrator has seen a full runningTimeConstantSeconds
+// worth of samples, its estimate hasn't settled and is not a meaningful
+// loudness reading (a single loud sample after silence would otherwise spike
+// it), so no gain is applied; nor is gain applied once settled if the
+// estimate still sits below AbsoluteGateLUFS, mirroring the absolute gate
+// MeasureLoudness applies to blocks.
+func (r *RunningNormalizer) GainDB() float64 {
+	if r.samplesSeen < r.warmupSamples {
+		return 0
+	}
+
+	lufs := powerToLUFS(r.runningPower)
+	if lufs < AbsoluteGateLUFS {
+		return 0
+	}
+
+	return GainForLoudness(lufs)
+}
+
+// Peak returns the largest absolute sample value seen so far.
+func (r *RunningNormalizer) Peak() float64 {
+	return r.peak
+}
@@ -0,0 +1,42 @@
+package audio
+
+import "testing"
+
+func TestRunningNormalizerNoGainAtStreamStart(t *testing.T) {
+	r := NewRunningNormalizer(48000)
+
+	if out := r.Process(1.0); out != 1.0 {
+		t.Errorf("Process(1.0) on the very first sample = %v, want 1 (no gain until the estimate clears the gate)", out)
+	}
+}
+
+func TestRunningNormalizerHoldsGateThroughQuietLeadIn(t *testing.T) {
+	const sampleRate = 48000
+
+	r := NewRunningNormalizer(sampleRate)
+
+	for i := 0; i < 1000; i++ {
+		r.Process(0)
+	}
+
+	out := r.Process(1.0)
+
+	if out != 1.0 {
+		t.Errorf("Process(1.0) right after a quiet lead-in = %v, want 1 (no spurious amplification)", out)
+	}
+}
+
+func TestRunningNormalizerGainConvergesTowardsReplayGainTarget(t *testing.T) {
+	const sampleRate = 48000
+
+	r := NewRunningNormalizer(sampleRate)
+
+	for i := 0; i < 15*sampleRate; i++ {
+		r.Process(sineAt(997, sampleRate, i))
+	}
+
+	want := ReferenceLoudnessLUFS - fullScaleSineLUFS
+	if diff := r.GainDB() - want; diff > 1 || diff < -1 {
+		t.Errorf("GainDB() after convergence = %v, want approximately %v", r.GainDB(), want)
+	}
+}
@@ -0,0 +1,14 @@
+// Package audio provides a pluggable sample-source abstraction that
+// downmixes multi-channel audio to mono and resamples it to whatever rate a
+// caller needs, so every fingerprinting algorithm sees a consistent signal
+// regardless of the source file's channel count or sample rate.
+package audio
+
+// SampleSource produces mono float64 samples, mirroring io.Reader's
+// contract: Read fills buf with up to len(buf) samples and returns how many
+// were read, returning io.EOF itself (not wrapped) once the source is
+// exhausted.
+type SampleSource interface {
+	Read(buf []float64) (int, error)
+	SampleRate() uint32
+}
@@ -11,6 +11,9 @@ import (
 	"os"
 	"runtime/pprof"
 
+	"github.com/Crystalix007/simsalabim/audio"
+	"github.com/Crystalix007/simsalabim/fingerprint/panako"
+	"github.com/Crystalix007/simsalabim/store"
 	"github.com/mewkiz/flac"
 	"github.com/mjibson/go-dsp/fft"
 )
@@ -18,19 +21,45 @@ import (
 const (
 	FFTChunkSeconds float64 = 0.3
 	FrequencyCount          = 3
+
+	AlgoTopN   = "topn"
+	AlgoPanako = "panako"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "identify" {
+		runIdentify(os.Args[2:])
+		return
+	}
+
+	runFingerprint(os.Args[1:])
+}
+
+func runFingerprint(args []string) {
+	fs := flag.NewFlagSet("fingerprint", flag.ExitOnError)
+
 	var (
 		filename string
 		output   string
 		profile  string
+		algo     string
+		toStore  bool
+		trackID  uint
+		window   int
+		hop      int
+		workers  int
 	)
 
-	flag.StringVar(&filename, "filename", "", "audio file to fingerprint")
-	flag.StringVar(&output, "output", "", "fingerprint output file")
-	flag.StringVar(&profile, "profile", "", "profile performance and write to file")
-	flag.Parse()
+	fs.StringVar(&filename, "filename", "", "audio file to fingerprint")
+	fs.StringVar(&output, "output", "", "fingerprint output file, or store directory when -store is set")
+	fs.StringVar(&profile, "profile", "", "profile performance and write to file")
+	fs.StringVar(&algo, "algo", AlgoTopN, "fingerprinting algorithm to use: topn or panako")
+	fs.BoolVar(&toStore, "store", false, "treat -output as a fingerprint store directory rather than a text file")
+	fs.UintVar(&trackID, "track", 0, "track ID to add fingerprints under when -store is set")
+	fs.IntVar(&window, "window", 0, "topn FFT window size in samples (default: FFTChunkSeconds worth of samples)")
+	fs.IntVar(&hop, "hop", 0, "topn FFT hop size in samples (default: 25% of the window, i.e. 75% overlap)")
+	fs.IntVar(&workers, "workers", 0, "topn FFT worker count (default: 4)")
+	fs.Parse(args)
 
 	if filename == "" {
 		log.Fatal("Must specify filename of audio file to fingerprint")
@@ -42,6 +71,24 @@ func main() {
 	}
 	defer audioStream.Close()
 
+	if profile != "" {
+		f, err := os.Create(profile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pprof.StartCPUProfile(f)
+		defer pprof.StopCPUProfile()
+	}
+
+	if toStore {
+		if algo != AlgoPanako {
+			log.Fatal("Must use -algo panako when writing fingerprints to a store")
+		}
+
+		writeStoreFingerprints(audioStream, output, uint32(trackID))
+		return
+	}
+
 	var outputFile *bufio.Writer
 
 	if output != "" {
@@ -56,16 +103,112 @@ func main() {
 		outputFile = bufio.NewWriter(f)
 	}
 
-	if profile != "" {
-		f, err := os.Create(profile)
-		if err != nil {
-			log.Fatal(err)
-		}
-		pprof.StartCPUProfile(f)
-		defer pprof.StopCPUProfile()
+	switch algo {
+	case AlgoPanako:
+		writePanakoFingerprints(audioStream, outputFile)
+	case AlgoTopN:
+		writeTopNFingerprints(audioStream, outputFile, window, hop, workers)
+	default:
+		log.Fatalf("Unknown fingerprinting algorithm: %q", algo)
 	}
 
-	fingerprints, err := fingerprint(audioStream)
+	if outputFile != nil {
+		outputFile.Flush()
+	}
+}
+
+func writeStoreFingerprints(audioStream *flac.Stream, storeDir string, trackID uint32) {
+	if storeDir == "" {
+		log.Fatal("Must specify -output store directory when -store is set")
+	}
+
+	samples, sampleRate, err := readSamples(audioStream)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	samples, gainDB, peak := audio.Normalize(samples, sampleRate)
+
+	s, err := store.Open(storeDir)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	metadata := store.TrackMetadata{ReplayGain: gainDB, ReplayPeak: peak}
+
+	if err := s.Add(trackID, panako.Fingerprint(samples, sampleRate), metadata); err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
+
+// runIdentify implements the `identify` subcommand: it fingerprints an audio
+// file and queries a fingerprint store for matching tracks.
+func runIdentify(args []string) {
+	fs := flag.NewFlagSet("identify", flag.ExitOnError)
+
+	var (
+		filename string
+		storeDir string
+	)
+
+	fs.StringVar(&filename, "filename", "", "audio file to identify")
+	fs.StringVar(&storeDir, "store", "", "fingerprint store directory to query")
+	fs.Parse(args)
+
+	if filename == "" || storeDir == "" {
+		log.Fatal("Must specify -filename and -store")
+	}
+
+	audioStream, err := flac.Open(filename)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+	defer audioStream.Close()
+
+	samples, sampleRate, err := readSamples(audioStream)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	s, err := store.Open(storeDir)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	matches, err := s.Identify(panako.Fingerprint(samples, sampleRate))
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	if len(matches) == 0 {
+		fmt.Println("No match found")
+		return
+	}
+
+	for _, match := range matches {
+		fmt.Printf("Track %d: offset=%.2fs hits=%d duration=%.2fs\n",
+			match.TrackID, match.Offset, match.Hits, match.Duration)
+	}
+}
+
+// writeTopNFingerprints fingerprints audioStream at panako.TargetSampleRate
+// regardless of its native rate, via audio.ResampleSource, so top-N
+// fingerprints from 44.1kHz, 48kHz, and 16kHz sources stay comparable.
+func writeTopNFingerprints(audioStream *flac.Stream, outputFile *bufio.Writer, window, hop, workers int) {
+	source := audio.NewResampleSource(audio.NewFLACSource(audioStream), panako.TargetSampleRate)
+	cfg := DefaultPipelineConfig(source.SampleRate())
+
+	if window > 0 {
+		cfg.WindowSize = window
+	}
+	if hop > 0 {
+		cfg.HopSize = hop
+	}
+	if workers > 0 {
+		cfg.Workers = workers
+	}
+
+	fingerprints, err := runPipeline(source, cfg)
 
 	if err != nil {
 		log.Fatalf("%+v", err)
@@ -81,57 +224,53 @@ func main() {
 
 			fmt.Fprintf(outputFile, "\n")
 		} else {
-			fmt.Printf("Fingerprint@%.2f:\n", fingerprint.timestamp)
+			fmt.Printf("Fingerprint@%.2f: (replayGain %.2fdB, replayPeak %.3f)\n",
+				fingerprint.timestamp, fingerprint.ReplayGain, fingerprint.ReplayPeak)
 			for _, freq := range fingerprint.frequencies {
 				fmt.Printf("\t%.1fHz\t (%.3f)\n", freq.frequency, freq.magnitude)
 			}
 		}
 	}
-
-	if outputFile != nil {
-		outputFile.Flush()
-	}
 }
 
-func fingerprint(stream *flac.Stream) ([]*TimestampFingerprint, error) {
-	sampleStream := make(chan float64)
-	sampleRate := stream.Info.SampleRate
-	fingerprintStream := fingerprintChan(sampleStream, sampleRate)
-	fingerprints := []*TimestampFingerprint{}
-	var err error = nil
+func writePanakoFingerprints(audioStream *flac.Stream, outputFile *bufio.Writer) {
+	samples, sampleRate, err := readSamples(audioStream)
 
-	go func() {
-		defer close(sampleStream)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
 
-		for {
-			frame, err := stream.ParseNext()
-			if err != nil {
-				if err == io.EOF {
-					err = nil
-				}
-				break
-			}
+	samples, gainDB, peak := audio.Normalize(samples, sampleRate)
+	log.Printf("Normalized track: replayGain=%.2fdB replayPeak=%.3f", gainDB, peak)
 
-			var denominator int = 1 << frame.BitsPerSample
+	for _, fingerprint := range panako.Fingerprint(samples, sampleRate) {
+		if outputFile != nil {
+			fmt.Fprintf(outputFile, "%x %.2f\n", uint64(fingerprint.Hash), fingerprint.Timestamp)
+		} else {
+			fmt.Printf("Hash@%.2f:\t%x\n", fingerprint.Timestamp, uint64(fingerprint.Hash))
+		}
+	}
+}
 
-			for i := 0; i < frame.Subframes[0].NSamples; i++ {
-				// TODO: Handle stereo
+// readSamples reads every downmixed mono sample out of stream, for
+// algorithms that need the whole signal up front rather than a running
+// chunk.
+func readSamples(stream *flac.Stream) ([]float64, uint32, error) {
+	source := audio.NewFLACSource(stream)
+	var samples []float64
+	chunk := make([]float64, 4096)
 
-				sampleStream <- float64(frame.Subframes[0].Samples[i]) / float64(denominator)
-				//for _, subframe := range frame.Subframes {
-				//	sampleStream <- float64(subframe.Samples[i]) / float64(denominator)
+	for {
+		n, err := source.Read(chunk)
+		samples = append(samples, chunk[:n]...)
 
-				//	break
-				//}
+		if err != nil {
+			if err == io.EOF {
+				return samples, source.SampleRate(), nil
 			}
+			return nil, 0, err
 		}
-	}()
-
-	for fingerprint := range fingerprintStream {
-		fingerprints = append(fingerprints, fingerprint)
 	}
-
-	return fingerprints, err
 }
 
 type TimestampFingerprint struct {
@@ -141,6 +280,13 @@ type TimestampFingerprint struct {
 
 type Fingerprint struct {
 	frequencies []FrequencyMagnitude
+
+	// ReplayGain and ReplayPeak record the running normalizer's gain and
+	// peak as of the end of the stream (the same values on every window,
+	// not the gain applied to that particular window), mirroring the replayGain/replayPeak fields of
+	// adjacent ecosystem tools so a downstream store can record them.
+	ReplayGain float64
+	ReplayPeak float64
 }
 
 func (f Fingerprint) Timestamp(timestamp float64) *TimestampFingerprint {
@@ -150,32 +296,6 @@ func (f Fingerprint) Timestamp(timestamp float64) *TimestampFingerprint {
 	}
 }
 
-func fingerprintChan(stream <-chan float64, sampleRate uint32) <-chan *TimestampFingerprint {
-	fingerprints := make(chan *TimestampFingerprint)
-	var chunkSize = int(float64(sampleRate) * FFTChunkSeconds)
-
-	go func() {
-		defer close(fingerprints)
-		var chunk = make([]float64, chunkSize)
-		var timestamp float64 = 0
-		var index = 0
-
-		for sample := range stream {
-			chunk[index] = sample
-			index++
-
-			if index == chunkSize {
-				fingerprints <- fingerprintChunk(chunk, sampleRate).Timestamp(timestamp)
-
-				timestamp += FFTChunkSeconds
-				index = 0
-			}
-		}
-	}()
-
-	return fingerprints
-}
-
 type FrequencyMagnitude struct {
 	frequency float64
 	magnitude float64
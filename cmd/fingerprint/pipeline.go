@@ -0,0 +1,188 @@
+package main
+
+import (
+	"container/heap"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/Crystalix007/simsalabim/audio"
+)
+
+// PipelineConfig configures the streaming top-N FFT pipeline: how large a
+// window each FFT covers, how far successive windows are spaced (smaller
+// than WindowSize means overlapping windows), and how many windows are
+// processed concurrently.
+type PipelineConfig struct {
+	WindowSize int
+	HopSize    int
+	Workers    int
+}
+
+// DefaultPipelineConfig returns sensible defaults for sampleRate: a
+// FFTChunkSeconds-long, 75%-overlapping Hann window, fanned out across 4
+// workers.
+func DefaultPipelineConfig(sampleRate uint32) PipelineConfig {
+	windowSize := int(float64(sampleRate) * FFTChunkSeconds)
+
+	return PipelineConfig{
+		WindowSize: windowSize,
+		HopSize:    windowSize / 4,
+		Workers:    4,
+	}
+}
+
+type windowJob struct {
+	index     int
+	timestamp float64
+	samples   []float64
+}
+
+type windowResult struct {
+	index       int
+	fingerprint *TimestampFingerprint
+}
+
+// runPipeline streams samples out of source, loudness-normalizes them,
+// slices them into overlapping Hann windows, and computes their fingerprints concurrently across cfg.Workers, yielding
+// TimestampFingerprints in window order regardless of which worker finishes
+// first.
+func runPipeline(source audio.SampleSource, cfg PipelineConfig) ([]*TimestampFingerprint, error) {
+	normalizer := audio.NewRunningNormalizer(source.SampleRate())
+
+	jobs, readErr := produceWindows(source, normalizer, cfg)
+	results := fanOutFFT(jobs, source.SampleRate(), cfg.Workers)
+	fingerprints := reorderResults(results)
+
+	for _, fp := range fingerprints {
+		fp.ReplayGain = normalizer.GainDB()
+		fp.ReplayPeak = normalizer.Peak()
+	}
+
+	return fingerprints, *readErr
+}
+
+// produceWindows reads source in large batches (not sample-by-sample) into a
+// slice buffer and emits overlapping windows of cfg.WindowSize samples,
+// cfg.HopSize apart, as soon as enough samples have accumulated. The buffer
+// is a plain slice advanced by cfg.HopSize per window, not a circular buffer,
+// and each window is copied out of it before being sent to the workers.
+func produceWindows(source audio.SampleSource, normalizer *audio.RunningNormalizer, cfg PipelineConfig) (<-chan windowJob, *error) {
+	jobs := make(chan windowJob)
+	var readErr error
+
+	go func() {
+		defer close(jobs)
+
+		var buffer []float64
+		index := 0
+		readChunk := make([]float64, 4096)
+
+		for {
+			n, err := source.Read(readChunk)
+
+			for _, sample := range readChunk[:n] {
+				buffer = append(buffer, normalizer.Process(sample))
+			}
+
+			for len(buffer) >= cfg.WindowSize {
+				window := make([]float64, cfg.WindowSize)
+				copy(window, buffer[:cfg.WindowSize])
+				applyHannWindow(window)
+
+				jobs <- windowJob{
+					index:     index,
+					timestamp: float64(index*cfg.HopSize) / float64(source.SampleRate()),
+					samples:   window,
+				}
+
+				index++
+				buffer = buffer[cfg.HopSize:]
+			}
+
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+		}
+	}()
+
+	return jobs, &readErr
+}
+
+// fanOutFFT runs cfg.Workers goroutines computing fingerprintChunk over
+// jobs concurrently, closing the returned channel once every job has been
+// processed.
+func fanOutFFT(jobs <-chan windowJob, sampleRate uint32, workers int) <-chan windowResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make(chan windowResult)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for job := range jobs {
+				fp := fingerprintChunk(job.samples, sampleRate).Timestamp(job.timestamp)
+				results <- windowResult{index: job.index, fingerprint: fp}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// reorderResults buffers out-of-order windowResults in a min-heap keyed by
+// window index and emits them once the next expected index is available,
+// so concurrent FFT workers don't reorder the output stream.
+func reorderResults(results <-chan windowResult) []*TimestampFingerprint {
+	pending := &resultHeap{}
+	fingerprints := []*TimestampFingerprint{}
+	next := 0
+
+	for result := range results {
+		heap.Push(pending, result)
+
+		for pending.Len() > 0 && (*pending)[0].index == next {
+			fingerprints = append(fingerprints, heap.Pop(pending).(windowResult).fingerprint)
+			next++
+		}
+	}
+
+	return fingerprints
+}
+
+type resultHeap []windowResult
+
+func (h resultHeap) Len() int            { return len(h) }
+func (h resultHeap) Less(i, j int) bool  { return h[i].index < h[j].index }
+func (h resultHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *resultHeap) Push(x interface{}) { *h = append(*h, x.(windowResult)) }
+func (h *resultHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// applyHannWindow tapers samples with a Hann window in place, so the
+// overlapping FFTs this pipeline computes don't ring at window boundaries.
+func applyHannWindow(samples []float64) {
+	n := len(samples)
+
+	for i := range samples {
+		samples[i] *= 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestReorderResultsRestoresWindowOrder(t *testing.T) {
+	const n = 10
+
+	results := make(chan windowResult)
+
+	go func() {
+		defer close(results)
+
+		// Feed indices out of order to simulate concurrent workers finishing
+		// in whatever order they happen to complete.
+		order := []int{3, 1, 0, 2, 5, 4, 7, 6, 9, 8}
+		for _, i := range order {
+			results <- windowResult{index: i, fingerprint: (Fingerprint{}).Timestamp(float64(i))}
+		}
+	}()
+
+	fingerprints := reorderResults(results)
+
+	if len(fingerprints) != n {
+		t.Fatalf("len(fingerprints) = %d, want %d", len(fingerprints), n)
+	}
+
+	for i, fp := range fingerprints {
+		if fp.timestamp != float64(i) {
+			t.Errorf("fingerprints[%d].timestamp = %v, want %v", i, fp.timestamp, float64(i))
+		}
+	}
+}
+
+func TestReorderResultsSingleWindow(t *testing.T) {
+	results := make(chan windowResult, 1)
+	results <- windowResult{index: 0, fingerprint: (Fingerprint{}).Timestamp(0)}
+	close(results)
+
+	fingerprints := reorderResults(results)
+
+	if len(fingerprints) != 1 {
+		t.Fatalf("len(fingerprints) = %d, want 1", len(fingerprints))
+	}
+}
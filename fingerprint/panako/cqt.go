@@ -0,0 +1,141 @@
+// Package panako implements a Panako-inspired acoustic fingerprinting
+// pipeline: a constant-Q spectrogram, local-maxima peak picking on the
+// log-frequency grid, and triplet-based peak hashing. The resulting hashes
+// are robust to small time shifts and moderate pitch/tempo changes, which
+// makes them suitable for hash-based catalog lookup rather than pairwise
+// comparison.
+package panako
+
+import "math"
+
+const (
+	// TargetSampleRate is the rate, in Hz, that incoming audio is resampled
+	// to before the constant-Q transform is computed.
+	TargetSampleRate = 16000
+
+	// MinFrequency and MaxFrequency bound the constant-Q spectrum, covering
+	// the range Panako itself uses.
+	MinFrequency = 110.0
+	MaxFrequency = 7040.0
+
+	// BinsPerOctave sets the frequency resolution of the log-spaced grid.
+	BinsPerOctave = 85
+
+	// HopSeconds is the spacing between successive CQT frames.
+	HopSeconds = 0.0058 // ~172 frames/sec, matching Panako's analysis rate
+)
+
+// CQTSpectrogram is a constant-Q magnitude spectrogram: Frames[t][b] is the
+// magnitude of frequency bin b at frame t. Bins are spaced logarithmically
+// between MinFrequency and MaxFrequency, BinsPerOctave apart.
+type CQTSpectrogram struct {
+	Frames     [][]float64
+	BinCount   int
+	HopSamples int
+	SampleRate uint32
+}
+
+// BinFrequency returns the center frequency of bin b.
+func (s *CQTSpectrogram) BinFrequency(b int) float64 {
+	return MinFrequency * math.Pow(2, float64(b)/BinsPerOctave)
+}
+
+// FrameTime returns the timestamp, in seconds, of frame t.
+func (s *CQTSpectrogram) FrameTime(t int) float64 {
+	return float64(t*s.HopSamples) / float64(s.SampleRate)
+}
+
+// ComputeCQT computes a constant-Q magnitude spectrogram of samples, which
+// must already be mono and sampled at sampleRate. Each bin is evaluated by
+// correlating the signal against a Hann-windowed complex exponential kernel
+// sized to give every bin a constant Q (center frequency / bandwidth).
+func ComputeCQT(samples []float64, sampleRate uint32) *CQTSpectrogram {
+	binCount := binCount()
+	q := 1 / (math.Pow(2, 1.0/BinsPerOctave) - 1)
+	hopSamples := int(HopSeconds * float64(sampleRate))
+	if hopSamples < 1 {
+		hopSamples = 1
+	}
+
+	kernels := make([]cqtKernel, binCount)
+	maxLength := 0
+
+	for b := 0; b < binCount; b++ {
+		freq := MinFrequency * math.Pow(2, float64(b)/BinsPerOctave)
+		length := int(q * float64(sampleRate) / freq)
+		if length < 1 {
+			length = 1
+		}
+		if length > maxLength {
+			maxLength = length
+		}
+
+		kernels[b] = newCQTKernel(q, length)
+	}
+
+	frameCount := 0
+	if len(samples) > maxLength {
+		frameCount = (len(samples)-maxLength)/hopSamples + 1
+	}
+
+	frames := make([][]float64, frameCount)
+
+	for t := 0; t < frameCount; t++ {
+		start := t * hopSamples
+		frame := make([]float64, binCount)
+
+		for b, kernel := range kernels {
+			frame[b] = kernel.magnitude(samples[start : start+kernel.length])
+		}
+
+		frames[t] = frame
+	}
+
+	return &CQTSpectrogram{
+		Frames:     frames,
+		BinCount:   binCount,
+		HopSamples: hopSamples,
+		SampleRate: sampleRate,
+	}
+}
+
+func binCount() int {
+	return int(math.Ceil(BinsPerOctave*math.Log2(MaxFrequency/MinFrequency))) + 1
+}
+
+// cqtKernel is a precomputed Hann-windowed complex exponential used to
+// correlate a single constant-Q bin against a window of samples.
+type cqtKernel struct {
+	real   []float64
+	imag   []float64
+	length int
+}
+
+func newCQTKernel(q float64, length int) cqtKernel {
+	real := make([]float64, length)
+	imag := make([]float64, length)
+
+	for n := 0; n < length; n++ {
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(n)/float64(length-1))
+		phase := 2 * math.Pi * q * float64(n) / float64(length)
+
+		real[n] = window * math.Cos(phase)
+		imag[n] = window * -math.Sin(phase)
+	}
+
+	return cqtKernel{real: real, imag: imag, length: length}
+}
+
+func (k cqtKernel) magnitude(samples []float64) float64 {
+	var re, im float64
+
+	for n, sample := range samples {
+		re += sample * k.real[n]
+		im += sample * k.imag[n]
+	}
+
+	re /= float64(k.length)
+	im /= float64(k.length)
+
+	return math.Hypot(re, im)
+}
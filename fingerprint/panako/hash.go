@@ -0,0 +1,121 @@
+package panako
+
+import "sort"
+
+const (
+	// MinDeltaFrames and MaxDeltaFrames bound how far apart in time two
+	// peaks may be to be paired into a hash.
+	MinDeltaFrames = 2
+	MaxDeltaFrames = 33
+
+	// MinDeltaBins and MaxDeltaBins bound how far apart in frequency two
+	// peaks may be to be paired into a hash.
+	MinDeltaBins = 1
+	MaxDeltaBins = 128
+)
+
+// Hash is a packed (anchor frequency, two frequency ratios, two time deltas)
+// tuple identifying a triplet of peaks. Because it is built from ratios and
+// deltas rather than absolute values, it is unaffected by a constant time
+// shift and tolerant of small, uniform pitch/tempo changes.
+type Hash uint64
+
+// HashedFingerprint is a single fingerprint hash anchored at timestamp.
+type HashedFingerprint struct {
+	Hash      Hash
+	Timestamp float64
+}
+
+// HashPeaks pairs every peak p1 with every pair of later peaks p2, p3 that
+// fall within [MinDeltaFrames, MaxDeltaFrames] in time and [MinDeltaBins,
+// MaxDeltaBins] in frequency, and packs each resulting triplet into a Hash
+// anchored at p1's timestamp.
+func HashPeaks(peaks []Peak) []HashedFingerprint {
+	byFrame := make([]Peak, len(peaks))
+	copy(byFrame, peaks)
+	sort.Slice(byFrame, func(i, j int) bool {
+		return byFrame[i].Frame < byFrame[j].Frame
+	})
+
+	var hashes []HashedFingerprint
+
+	for i, p1 := range byFrame {
+		candidates := candidatesFor(byFrame, i, p1)
+
+		for a := 0; a < len(candidates); a++ {
+			for b := a + 1; b < len(candidates); b++ {
+				p2, p3 := candidates[a], candidates[b]
+
+				hashes = append(hashes, HashedFingerprint{
+					Hash:      packTriplet(p1, p2, p3),
+					Timestamp: p1.Timestamp,
+				})
+			}
+		}
+	}
+
+	return hashes
+}
+
+func candidatesFor(peaks []Peak, i int, p1 Peak) []Peak {
+	var candidates []Peak
+
+	for j := i + 1; j < len(peaks); j++ {
+		p2 := peaks[j]
+		deltaFrame := p2.Frame - p1.Frame
+
+		if deltaFrame > MaxDeltaFrames {
+			break
+		}
+		if deltaFrame < MinDeltaFrames {
+			continue
+		}
+
+		deltaBin := p2.Bin - p1.Bin
+		if deltaBin < 0 {
+			deltaBin = -deltaBin
+		}
+		if deltaBin < MinDeltaBins || deltaBin > MaxDeltaBins {
+			continue
+		}
+
+		candidates = append(candidates, p2)
+	}
+
+	return candidates
+}
+
+// packTriplet packs (f1, f2/f1, f3/f1, t2-t1, t3-t1) into a 64-bit hash. The
+// anchor frequency bin and the two ratios are quantized to fit alongside the
+// two bounded time deltas.
+func packTriplet(p1, p2, p3 Peak) Hash {
+	f1 := uint64(p1.Bin) & 0x1FF // 9 bits: up to 512 CQT bins
+
+	ratio2 := uint64(quantizeRatio(p2.Bin, p1.Bin)) // 8 bits: covers ±MaxDeltaBins
+	ratio3 := uint64(quantizeRatio(p3.Bin, p1.Bin))
+
+	dt2 := uint64(p2.Frame-p1.Frame) & 0x3F // 6 bits: MaxDeltaFrames < 64
+	dt3 := uint64(p3.Frame-p1.Frame) & 0x3F
+
+	return Hash(f1 | ratio2<<9 | ratio3<<17 | dt2<<25 | dt3<<31)
+}
+
+// quantizeRatio maps a bin delta to an 8-bit code centered on "equal", so
+// that ratios close to 1 (as produced by small pitch shifts) still collide.
+// candidatesFor only ever pairs bins MinDeltaBins..MaxDeltaBins apart, so
+// delta is never zero; the 256 values delta can take (+/-1..MaxDeltaBins)
+// map bijectively onto the 8-bit code, so distinct deltas never alias.
+func quantizeRatio(bin, anchorBin int) uint32 {
+	delta := bin - anchorBin
+	if delta < -MaxDeltaBins {
+		delta = -MaxDeltaBins
+	}
+	if delta > MaxDeltaBins {
+		delta = MaxDeltaBins
+	}
+	if delta > 0 {
+		delta--
+	}
+
+	return uint32(delta+MaxDeltaBins) & 0xFF
+}
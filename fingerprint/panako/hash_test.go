@@ -0,0 +1,109 @@
+package panako
+
+import "testing"
+
+func TestQuantizeRatioBijective(t *testing.T) {
+	seen := make(map[uint32]int)
+
+	for delta := -MaxDeltaBins; delta <= MaxDeltaBins; delta++ {
+		if delta == 0 {
+			continue
+		}
+
+		code := quantizeRatio(MaxDeltaBins+delta, MaxDeltaBins)
+		if code > 0xFF {
+			t.Fatalf("quantizeRatio(delta=%d) = %d, want <= 0xFF", delta, code)
+		}
+
+		seen[code]++
+	}
+
+	for code, count := range seen {
+		if count > 1 {
+			t.Errorf("code %d produced by %d distinct deltas, want a bijection", code, count)
+		}
+	}
+}
+
+func TestQuantizeRatioClamps(t *testing.T) {
+	over := quantizeRatio(MaxDeltaBins+1000, 0)
+	atMax := quantizeRatio(MaxDeltaBins, 0)
+
+	if over != atMax {
+		t.Errorf("quantizeRatio with delta beyond MaxDeltaBins = %d, want clamp to MaxDeltaBins code %d", over, atMax)
+	}
+}
+
+func TestPackTripletRoundTripsBinAndDeltas(t *testing.T) {
+	p1 := Peak{Frame: 10, Bin: 50}
+	p2 := Peak{Frame: 15, Bin: 50 + MaxDeltaBins}
+	p3 := Peak{Frame: 20, Bin: 50 - MaxDeltaBins}
+
+	h := packTriplet(p1, p2, p3)
+
+	if f1 := uint64(h) & 0x1FF; f1 != uint64(p1.Bin) {
+		t.Errorf("packed f1 = %d, want %d", f1, p1.Bin)
+	}
+
+	dt2 := uint64(h) >> 25 & 0x3F
+	dt3 := uint64(h) >> 31 & 0x3F
+	if dt2 != uint64(p2.Frame-p1.Frame) {
+		t.Errorf("packed dt2 = %d, want %d", dt2, p2.Frame-p1.Frame)
+	}
+	if dt3 != uint64(p3.Frame-p1.Frame) {
+		t.Errorf("packed dt3 = %d, want %d", dt3, p3.Frame-p1.Frame)
+	}
+}
+
+// TestCandidatesForEnforcesDeltaBounds checks that candidatesFor only keeps
+// later peaks within [MinDeltaFrames, MaxDeltaFrames] in time and
+// [MinDeltaBins, MaxDeltaBins] in frequency of the anchor, rejecting peaks
+// that are too close or too far in either dimension. peaks must be sorted by
+// Frame ascending, matching how HashPeaks calls candidatesFor.
+func TestCandidatesForEnforcesDeltaBounds(t *testing.T) {
+	p1 := Peak{Frame: 10, Bin: 50}
+
+	peaks := []Peak{
+		p1,
+		{Frame: p1.Frame + MinDeltaFrames - 1, Bin: p1.Bin + MinDeltaBins}, // too close in time
+		{Frame: p1.Frame + MinDeltaFrames, Bin: p1.Bin},                    // deltaBin 0, below MinDeltaBins
+		{Frame: p1.Frame + MinDeltaFrames, Bin: p1.Bin + MaxDeltaBins + 1}, // too far in frequency
+		{Frame: p1.Frame + MinDeltaFrames, Bin: p1.Bin + MinDeltaBins},     // valid: lower bound on both
+		{Frame: p1.Frame + MaxDeltaFrames, Bin: p1.Bin - MaxDeltaBins},     // valid: upper bound, negative delta
+		{Frame: p1.Frame + MaxDeltaFrames + 1, Bin: p1.Bin + MinDeltaBins}, // too far in time
+	}
+
+	candidates := candidatesFor(peaks, 0, p1)
+
+	if len(candidates) != 2 {
+		t.Fatalf("candidatesFor() returned %d candidates, want 2: %+v", len(candidates), candidates)
+	}
+
+	for _, c := range candidates {
+		deltaFrame := c.Frame - p1.Frame
+		if deltaFrame < MinDeltaFrames || deltaFrame > MaxDeltaFrames {
+			t.Errorf("candidate %+v has out-of-bounds deltaFrame %d", c, deltaFrame)
+		}
+
+		deltaBin := c.Bin - p1.Bin
+		if deltaBin < 0 {
+			deltaBin = -deltaBin
+		}
+		if deltaBin < MinDeltaBins || deltaBin > MaxDeltaBins {
+			t.Errorf("candidate %+v has out-of-bounds deltaBin %d", c, deltaBin)
+		}
+	}
+}
+
+func TestPackTripletDoesNotAliasOppositeExtremeDeltas(t *testing.T) {
+	p1 := Peak{Frame: 10, Bin: 50}
+	pPos := Peak{Frame: 15, Bin: 50 + MaxDeltaBins}
+	pNeg := Peak{Frame: 15, Bin: 50 - MaxDeltaBins}
+
+	hPos := packTriplet(p1, pPos, p1)
+	hNeg := packTriplet(p1, pNeg, p1)
+
+	if hPos == hNeg {
+		t.Errorf("packTriplet aliased +MaxDeltaBins and -MaxDeltaBins to the same hash %d", hPos)
+	}
+}
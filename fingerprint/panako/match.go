@@ -0,0 +1,91 @@
+package panako
+
+import "sort"
+
+const (
+	// MinHits is the minimum number of colliding hashes a candidate offset
+	// must accumulate before it is accepted as a match.
+	MinHits = 5
+
+	// MinDurationSeconds is the minimum span between the first and last
+	// matching hash for a candidate offset to be accepted as a match.
+	MinDurationSeconds = 1.0
+)
+
+// CompareResult is the outcome of matching a probe's fingerprints against a
+// reference's: the best-scoring time offset between the two, how many
+// hashes collided at that offset, and the duration of audio that offset
+// covers.
+type CompareResult struct {
+	Offset   float64
+	Hits     int
+	Duration float64
+	Matched  bool
+}
+
+// Compare builds a time-offset histogram between query and reference hashes
+// and returns the tallest bin. For each hash shared between the two, the
+// difference between the reference and query timestamps is quantized to the
+// frame period and tallied; the offset with the most hits wins. Matched
+// reports whether that offset also clears MinHits and MinDurationSeconds.
+func Compare(query, reference []HashedFingerprint) CompareResult {
+	refTimestamps := make(map[Hash][]float64, len(reference))
+	for _, fp := range reference {
+		refTimestamps[fp.Hash] = append(refTimestamps[fp.Hash], fp.Timestamp)
+	}
+
+	type bin struct {
+		count int
+		times []float64
+	}
+
+	histogram := map[int64]*bin{}
+
+	for _, fp := range query {
+		for _, refTime := range refTimestamps[fp.Hash] {
+			delta := refTime - fp.Timestamp
+			bucket := quantizeDelta(delta)
+
+			b, ok := histogram[bucket]
+			if !ok {
+				b = &bin{}
+				histogram[bucket] = b
+			}
+
+			b.count++
+			b.times = append(b.times, fp.Timestamp)
+		}
+	}
+
+	var best CompareResult
+	var bestBucket int64
+
+	for bucket, b := range histogram {
+		if b.count > best.Hits {
+			best.Hits = b.count
+			best.Duration = duration(b.times)
+			bestBucket = bucket
+		}
+	}
+
+	best.Offset = float64(bestBucket) * HopSeconds
+	best.Matched = best.Hits >= MinHits && best.Duration >= MinDurationSeconds
+
+	return best
+}
+
+func quantizeDelta(delta float64) int64 {
+	return int64(delta / HopSeconds)
+}
+
+func duration(times []float64) float64 {
+	if len(times) == 0 {
+		return 0
+	}
+
+	sorted := make([]float64, len(times))
+	copy(sorted, times)
+	sort.Float64s(sorted)
+
+	return sorted[len(sorted)-1] - sorted[0]
+}
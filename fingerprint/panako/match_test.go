@@ -0,0 +1,59 @@
+package panako
+
+import "testing"
+
+func TestCompareMatchesAtConsistentOffset(t *testing.T) {
+	const (
+		offset  = 2.0
+		spacing = MinDurationSeconds / (MinHits - 1)
+	)
+
+	var query, reference []HashedFingerprint
+
+	for i := 0; i < MinHits; i++ {
+		h := Hash(i)
+		ts := float64(i) * spacing
+
+		query = append(query, HashedFingerprint{Hash: h, Timestamp: ts})
+		reference = append(reference, HashedFingerprint{Hash: h, Timestamp: ts + offset})
+	}
+
+	result := Compare(query, reference)
+
+	if !result.Matched {
+		t.Fatalf("Compare() = %+v, want Matched", result)
+	}
+
+	wantOffset := float64(quantizeDelta(offset)) * HopSeconds
+	if result.Offset != wantOffset {
+		t.Errorf("Offset = %v, want %v", result.Offset, wantOffset)
+	}
+	if result.Hits != MinHits {
+		t.Errorf("Hits = %d, want %d", result.Hits, MinHits)
+	}
+}
+
+func TestCompareRejectsTooFewHits(t *testing.T) {
+	var query, reference []HashedFingerprint
+
+	for i := 0; i < MinHits-1; i++ {
+		h := Hash(i)
+		ts := float64(i) * HopSeconds
+
+		query = append(query, HashedFingerprint{Hash: h, Timestamp: ts})
+		reference = append(reference, HashedFingerprint{Hash: h, Timestamp: ts})
+	}
+
+	if result := Compare(query, reference); result.Matched {
+		t.Errorf("Compare() = %+v, want not Matched with fewer than MinHits hashes", result)
+	}
+}
+
+func TestCompareRejectsNoOverlap(t *testing.T) {
+	query := []HashedFingerprint{{Hash: 1, Timestamp: 0}}
+	reference := []HashedFingerprint{{Hash: 2, Timestamp: 0}}
+
+	if result := Compare(query, reference); result.Matched {
+		t.Errorf("Compare() = %+v, want not Matched with no shared hashes", result)
+	}
+}
@@ -0,0 +1,14 @@
+package panako
+
+import "github.com/Crystalix007/simsalabim/audio"
+
+// Fingerprint runs the full Panako-style pipeline over mono samples captured
+// at sampleRate: resample to TargetSampleRate, compute a constant-Q
+// spectrogram, pick spectral peaks, and hash peak triplets.
+func Fingerprint(samples []float64, sampleRate uint32) []HashedFingerprint {
+	resampled := audio.Resample(samples, sampleRate, TargetSampleRate)
+	spectrogram := ComputeCQT(resampled, TargetSampleRate)
+	peaks := FindPeaks(spectrogram)
+
+	return HashPeaks(peaks)
+}
@@ -0,0 +1,70 @@
+package panako
+
+const (
+	// FrequencyWindow and TimeWindow are the neighborhood sizes (in bins and
+	// frames respectively) that a spectrogram value must dominate to be kept
+	// as a peak. Both must be applied on the log-frequency grid the CQT
+	// already produces.
+	FrequencyWindow = 103
+	TimeWindow      = 25
+)
+
+// Peak is a local maximum in the constant-Q spectrogram, identified by its
+// frame and bin indices plus the timestamp/frequency those correspond to.
+type Peak struct {
+	Frame     int
+	Bin       int
+	Timestamp float64
+	Frequency float64
+	Magnitude float64
+}
+
+// FindPeaks returns every spectrogram value that is the maximum within a
+// FrequencyWindow-bin by TimeWindow-frame neighborhood centered on it. Frames
+// near the start/end of the spectrogram are buffered internally so the
+// time-max filter always sees a full window before a peak is emitted; values
+// for which a full window would run off either edge are skipped.
+func FindPeaks(spec *CQTSpectrogram) []Peak {
+	halfFreq := FrequencyWindow / 2
+	halfTime := TimeWindow / 2
+
+	var peaks []Peak
+
+	for t := halfTime; t < len(spec.Frames)-halfTime; t++ {
+		for b := halfFreq; b < spec.BinCount-halfFreq; b++ {
+			value := spec.Frames[t][b]
+
+			if !isLocalMaximum(spec, t, b, value, halfTime, halfFreq) {
+				continue
+			}
+
+			peaks = append(peaks, Peak{
+				Frame:     t,
+				Bin:       b,
+				Timestamp: spec.FrameTime(t),
+				Frequency: spec.BinFrequency(b),
+				Magnitude: value,
+			})
+		}
+	}
+
+	return peaks
+}
+
+func isLocalMaximum(spec *CQTSpectrogram, t, b int, value float64, halfTime, halfFreq int) bool {
+	for dt := -halfTime; dt <= halfTime; dt++ {
+		row := spec.Frames[t+dt]
+
+		for db := -halfFreq; db <= halfFreq; db++ {
+			if dt == 0 && db == 0 {
+				continue
+			}
+
+			if row[b+db] >= value {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,57 @@
+package panako
+
+import "testing"
+
+// newFlatSpectrogram builds a silent spectrogram of the given size, for
+// tests that only care about a handful of hand-placed values.
+func newFlatSpectrogram(frames, bins int) *CQTSpectrogram {
+	f := make([][]float64, frames)
+	for i := range f {
+		f[i] = make([]float64, bins)
+	}
+
+	return &CQTSpectrogram{Frames: f, BinCount: bins, HopSamples: 1, SampleRate: TargetSampleRate}
+}
+
+// TestFindPeaksSkipsValuesTooCloseToSpectrogramEdge builds a spectrogram one
+// frame and one bin larger than a full FrequencyWindow x TimeWindow
+// neighborhood and places a value on the first frame/bin a full window can
+// be centered on. A far larger value sits at the very edge of the
+// spectrogram (last frame, last bin), just outside that neighborhood: even
+// though it dominates every neighbor it has, a full window can never be
+// centered on it, so FindPeaks must never consider it a peak.
+func TestFindPeaksSkipsValuesTooCloseToSpectrogramEdge(t *testing.T) {
+	halfFreq := FrequencyWindow / 2
+	halfTime := TimeWindow / 2
+
+	spec := newFlatSpectrogram(2*halfTime+2, 2*halfFreq+2)
+
+	spec.Frames[2*halfTime+1][2*halfFreq+1] = 1000
+	spec.Frames[halfTime][halfFreq] = 5
+
+	peaks := FindPeaks(spec)
+
+	if len(peaks) != 1 {
+		t.Fatalf("FindPeaks() returned %d peaks, want 1: %+v", len(peaks), peaks)
+	}
+	if peaks[0].Frame != halfTime || peaks[0].Bin != halfFreq {
+		t.Errorf("FindPeaks()[0] = {Frame: %d, Bin: %d}, want {%d, %d}", peaks[0].Frame, peaks[0].Bin, halfTime, halfFreq)
+	}
+}
+
+// TestFindPeaksRejectsATiedNeighbor checks that a value is only kept as a
+// peak when it strictly dominates its whole neighborhood; a neighbor tied
+// with it must disqualify both.
+func TestFindPeaksRejectsATiedNeighbor(t *testing.T) {
+	halfFreq := FrequencyWindow / 2
+	halfTime := TimeWindow / 2
+
+	spec := newFlatSpectrogram(2*halfTime+1, 2*halfFreq+1)
+
+	spec.Frames[halfTime][halfFreq] = 5
+	spec.Frames[halfTime][halfFreq+1] = 5
+
+	if peaks := FindPeaks(spec); len(peaks) != 0 {
+		t.Errorf("FindPeaks() = %d peaks, want 0 (a tied neighbor must not be dominated)", len(peaks))
+	}
+}
@@ -0,0 +1,223 @@
+package store
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+
+	"github.com/Crystalix007/simsalabim/fingerprint/panako"
+)
+
+// trackRecordSize is the byte size of one (hash, timestamp) record in a
+// per-track hashes file.
+const trackRecordSize = 8 + 4
+
+// appendTrackHashes appends fingerprints to the per-track hashes file at
+// path, creating it if necessary. The file is append-only: existing records
+// are never rewritten.
+func appendTrackHashes(path string, fingerprints []panako.HashedFingerprint) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, trackRecordSize)
+
+	for _, fp := range fingerprints {
+		binary.LittleEndian.PutUint64(buf[0:8], uint64(fp.Hash))
+		binary.LittleEndian.PutUint32(buf[8:12], math.Float32bits(float32(fp.Timestamp)))
+
+		if _, err := f.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexEntry is a single record in the global inverted index: a hash and
+// the posting (track, timestamp) it occurred at.
+type indexEntry struct {
+	Hash      panako.Hash
+	TrackID   uint32
+	Timestamp float32
+}
+
+// indexRecordSize is the byte size of one indexEntry on disk.
+const indexRecordSize = 8 + 4 + 4
+
+func readIndexEntries(path string) ([]indexEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]indexEntry, 0, len(data)/indexRecordSize)
+	for off := 0; off+indexRecordSize <= len(data); off += indexRecordSize {
+		entries = append(entries, decodeIndexEntry(data[off:off+indexRecordSize]))
+	}
+
+	return entries, nil
+}
+
+func writeIndexEntries(path string, entries []indexEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, indexRecordSize)
+	for _, e := range entries {
+		encodeIndexEntry(buf, e)
+		if _, err := f.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeIndexEntry(buf []byte, e indexEntry) {
+	binary.LittleEndian.PutUint64(buf[0:8], uint64(e.Hash))
+	binary.LittleEndian.PutUint32(buf[8:12], e.TrackID)
+	binary.LittleEndian.PutUint32(buf[12:16], math.Float32bits(e.Timestamp))
+}
+
+func decodeIndexEntry(buf []byte) indexEntry {
+	return indexEntry{
+		Hash:      panako.Hash(binary.LittleEndian.Uint64(buf[0:8])),
+		TrackID:   binary.LittleEndian.Uint32(buf[8:12]),
+		Timestamp: math.Float32frombits(binary.LittleEndian.Uint32(buf[12:16])),
+	}
+}
+
+// offsetEntry marks the byte offset into index.dat of an entry with Hash,
+// recorded every SparseIndexInterval entries. The sampled entry need not be
+// the first one with that hash.
+type offsetEntry struct {
+	Hash   panako.Hash
+	Offset int64
+}
+
+const offsetRecordSize = 8 + 8
+
+func buildSparseIndex(entries []indexEntry) ([]offsetEntry, error) {
+	var offsets []offsetEntry
+
+	for i, e := range entries {
+		if i%SparseIndexInterval == 0 {
+			offsets = append(offsets, offsetEntry{
+				Hash:   e.Hash,
+				Offset: int64(i) * indexRecordSize,
+			})
+		}
+	}
+
+	return offsets, nil
+}
+
+func readOffsets(path string) ([]offsetEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	offsets := make([]offsetEntry, 0, len(data)/offsetRecordSize)
+	for off := 0; off+offsetRecordSize <= len(data); off += offsetRecordSize {
+		offsets = append(offsets, offsetEntry{
+			Hash:   panako.Hash(binary.LittleEndian.Uint64(data[off : off+8])),
+			Offset: int64(binary.LittleEndian.Uint64(data[off+8 : off+16])),
+		})
+	}
+
+	return offsets, nil
+}
+
+func writeOffsets(path string, offsets []offsetEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, offsetRecordSize)
+	for _, o := range offsets {
+		binary.LittleEndian.PutUint64(buf[0:8], uint64(o.Hash))
+		binary.LittleEndian.PutUint64(buf[8:16], uint64(o.Offset))
+
+		if _, err := f.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lookupIndexEntries returns every index entry for hash, using the sparse
+// offsets to seek close to the matching run before scanning linearly.
+func lookupIndexEntries(path string, offsets []offsetEntry, hash panako.Hash) ([]indexEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	start := startOffsetFor(offsets, hash)
+
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var matches []indexEntry
+	buf := make([]byte, indexRecordSize)
+
+	for {
+		if _, err := io.ReadFull(f, buf); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return nil, err
+		}
+
+		entry := decodeIndexEntry(buf)
+
+		if entry.Hash > hash {
+			break
+		}
+		if entry.Hash == hash {
+			matches = append(matches, entry)
+		}
+	}
+
+	return matches, nil
+}
+
+// startOffsetFor returns the byte offset of the last sparse entry whose hash
+// is strictly less than hash, or 0 if there is none. A sampled entry whose
+// hash equals hash may land in the middle of a run of entries sharing that
+// hash rather than at its start, so such entries must never be used as the
+// scan's starting point or earlier postings in the run would be missed.
+func startOffsetFor(offsets []offsetEntry, hash panako.Hash) int64 {
+	var start int64
+
+	for _, o := range offsets {
+		if o.Hash >= hash {
+			break
+		}
+		start = o.Offset
+	}
+
+	return start
+}
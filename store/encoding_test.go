@@ -0,0 +1,47 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Crystalix007/simsalabim/fingerprint/panako"
+)
+
+// TestLookupIndexEntriesAcrossSparseBoundary builds a run of entries sharing
+// one hash that straddles a SparseIndexInterval sample boundary (the sample
+// falls inside the run rather than at its start) and checks that every entry
+// in the run is still returned, not just the ones from the sampled offset
+// onward.
+func TestLookupIndexEntriesAcrossSparseBoundary(t *testing.T) {
+	const (
+		runHash  = panako.Hash(100)
+		runStart = 50
+		runLen   = 150
+	)
+
+	var entries []indexEntry
+	for i := 0; i < runStart; i++ {
+		entries = append(entries, indexEntry{Hash: panako.Hash(i), TrackID: 0, Timestamp: float32(i)})
+	}
+	for i := 0; i < runLen; i++ {
+		entries = append(entries, indexEntry{Hash: runHash, TrackID: uint32(i), Timestamp: float32(i)})
+	}
+
+	offsets, err := buildSparseIndex(entries)
+	if err != nil {
+		t.Fatalf("buildSparseIndex() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "index.dat")
+	if err := writeIndexEntries(path, entries); err != nil {
+		t.Fatalf("writeIndexEntries() error = %v", err)
+	}
+
+	matches, err := lookupIndexEntries(path, offsets, runHash)
+	if err != nil {
+		t.Fatalf("lookupIndexEntries() error = %v", err)
+	}
+	if len(matches) != runLen {
+		t.Errorf("lookupIndexEntries(%d) returned %d entries, want %d", runHash, len(matches), runLen)
+	}
+}
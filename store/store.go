@@ -0,0 +1,287 @@
+// Package store persists fingerprint hashes to disk in a layout designed
+// for hash lookup rather than linear rescanning: one append-only per-track
+// hashes file (modelled on Prometheus's per-series chunk files) plus a
+// single sorted, sparsely-indexed inverted index mapping hash to the
+// postings that share it. New postings land in small sorted segment files
+// rather than rewriting the whole index, and are folded in by periodic
+// compaction.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Crystalix007/simsalabim/fingerprint/panako"
+)
+
+const (
+	indexFilename   = "index.dat"
+	offsetsFilename = "index.offsets"
+	tracksDir       = "tracks"
+	segmentsDir     = "segments"
+
+	// SparseIndexInterval is how many index entries separate each entry
+	// recorded in the sparse offset index.
+	SparseIndexInterval = 128
+
+	// CompactionThreshold is how many pending segment files Add tolerates
+	// before merging them into the main index, bounding how many segments
+	// Lookup must linearly scan.
+	CompactionThreshold = 8
+)
+
+// Posting is a single occurrence of a hash within a track, as recorded in
+// the global inverted index.
+type Posting struct {
+	TrackID   uint32
+	Timestamp float32
+}
+
+// Match is a candidate identification of a query against a stored track.
+type Match struct {
+	TrackID uint32
+	panako.CompareResult
+}
+
+// TrackMetadata holds per-track loudness measurements, mirroring the
+// replayGain/replayPeak fields of adjacent ecosystem tools, so downstream
+// consumers can undo fingerprint-time normalization if needed.
+type TrackMetadata struct {
+	ReplayGain float64
+	ReplayPeak float64
+}
+
+// Store is an on-disk fingerprint index rooted at a directory.
+type Store struct {
+	dir     string
+	offsets []offsetEntry
+}
+
+// Open opens (creating if necessary) a Store rooted at dir.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, tracksDir), 0o755); err != nil {
+		return nil, fmt.Errorf("store: creating %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, segmentsDir), 0o755); err != nil {
+		return nil, fmt.Errorf("store: creating %s: %w", dir, err)
+	}
+
+	s := &Store{dir: dir}
+
+	offsets, err := readOffsets(s.offsetsPath())
+	if err != nil {
+		return nil, fmt.Errorf("store: reading sparse index: %w", err)
+	}
+	s.offsets = offsets
+
+	return s, nil
+}
+
+func (s *Store) indexPath() string   { return filepath.Join(s.dir, indexFilename) }
+func (s *Store) offsetsPath() string { return filepath.Join(s.dir, offsetsFilename) }
+func (s *Store) trackPath(trackID uint32) string {
+	return filepath.Join(s.dir, tracksDir, fmt.Sprintf("%d.hashes", trackID))
+}
+
+func (s *Store) metadataPath(trackID uint32) string {
+	return filepath.Join(s.dir, tracksDir, fmt.Sprintf("%d.json", trackID))
+}
+
+func (s *Store) segmentsGlob() string {
+	return filepath.Join(s.dir, segmentsDir, "*.seg")
+}
+
+func (s *Store) segmentPath(n int) string {
+	return filepath.Join(s.dir, segmentsDir, fmt.Sprintf("%d.seg", n))
+}
+
+// Add records fingerprints and metadata under trackID: it appends the
+// fingerprints to the track's own hashes file, writes them as a new sorted
+// segment file, and writes its loudness metadata alongside them. Segments
+// are folded into the main inverted index only once CompactionThreshold of
+// them are pending, when Add calls compact, so most calls cost O(k) and the
+// O(catalog size) rewrite is paid once every CompactionThreshold calls.
+func (s *Store) Add(trackID uint32, fingerprints []panako.HashedFingerprint, metadata TrackMetadata) error {
+	if err := appendTrackHashes(s.trackPath(trackID), fingerprints); err != nil {
+		return fmt.Errorf("store: writing track %d: %w", trackID, err)
+	}
+
+	if err := writeTrackMetadata(s.metadataPath(trackID), metadata); err != nil {
+		return fmt.Errorf("store: writing track %d metadata: %w", trackID, err)
+	}
+
+	entries := make([]indexEntry, len(fingerprints))
+	for i, fp := range fingerprints {
+		entries[i] = indexEntry{
+			Hash:      fp.Hash,
+			TrackID:   trackID,
+			Timestamp: float32(fp.Timestamp),
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Hash < entries[j].Hash
+	})
+
+	segments, err := filepath.Glob(s.segmentsGlob())
+	if err != nil {
+		return fmt.Errorf("store: listing segments: %w", err)
+	}
+
+	if err := writeIndexEntries(s.segmentPath(len(segments)), entries); err != nil {
+		return fmt.Errorf("store: writing segment: %w", err)
+	}
+	segments = append(segments, s.segmentPath(len(segments)))
+
+	if len(segments) >= CompactionThreshold {
+		if err := s.compact(segments); err != nil {
+			return fmt.Errorf("store: compacting index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// compact merges segments and the main index into a single sorted index,
+// rebuilds the sparse offset index over the result, and removes the
+// consumed segment files, amortizing the O(catalog size) rewrite cost
+// across CompactionThreshold calls to Add.
+func (s *Store) compact(segments []string) error {
+	entries, err := readIndexEntries(s.indexPath())
+	if err != nil {
+		return fmt.Errorf("reading index: %w", err)
+	}
+
+	for _, segment := range segments {
+		segmentEntries, err := readIndexEntries(segment)
+		if err != nil {
+			return fmt.Errorf("reading segment %s: %w", segment, err)
+		}
+		entries = append(entries, segmentEntries...)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Hash < entries[j].Hash
+	})
+
+	if err := writeIndexEntries(s.indexPath(), entries); err != nil {
+		return fmt.Errorf("writing index: %w", err)
+	}
+
+	offsets, err := buildSparseIndex(entries)
+	if err != nil {
+		return fmt.Errorf("building sparse index: %w", err)
+	}
+	if err := writeOffsets(s.offsetsPath(), offsets); err != nil {
+		return fmt.Errorf("writing sparse index: %w", err)
+	}
+	s.offsets = offsets
+
+	for _, segment := range segments {
+		if err := os.Remove(segment); err != nil {
+			return fmt.Errorf("removing segment %s: %w", segment, err)
+		}
+	}
+
+	return nil
+}
+
+// Metadata returns the loudness metadata recorded for trackID.
+func (s *Store) Metadata(trackID uint32) (TrackMetadata, error) {
+	data, err := os.ReadFile(s.metadataPath(trackID))
+	if err != nil {
+		return TrackMetadata{}, fmt.Errorf("store: reading track %d metadata: %w", trackID, err)
+	}
+
+	var metadata TrackMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return TrackMetadata{}, fmt.Errorf("store: decoding track %d metadata: %w", trackID, err)
+	}
+
+	return metadata, nil
+}
+
+func writeTrackMetadata(path string, metadata TrackMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Lookup returns every posting recorded against hash, from both the main
+// index and any segments still awaiting compaction.
+func (s *Store) Lookup(hash panako.Hash) ([]Posting, error) {
+	entries, err := lookupIndexEntries(s.indexPath(), s.offsets, hash)
+	if err != nil {
+		return nil, fmt.Errorf("store: looking up hash: %w", err)
+	}
+
+	segments, err := filepath.Glob(s.segmentsGlob())
+	if err != nil {
+		return nil, fmt.Errorf("store: listing segments: %w", err)
+	}
+
+	for _, segment := range segments {
+		segmentEntries, err := readIndexEntries(segment)
+		if err != nil {
+			return nil, fmt.Errorf("store: reading segment %s: %w", segment, err)
+		}
+
+		for _, e := range segmentEntries {
+			if e.Hash == hash {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	postings := make([]Posting, len(entries))
+	for i, e := range entries {
+		postings[i] = Posting{TrackID: e.TrackID, Timestamp: e.Timestamp}
+	}
+
+	return postings, nil
+}
+
+// Identify matches query against every track that shares at least one hash
+// with it, running the time-offset histogram matcher (panako.Compare) over
+// each candidate and returning every candidate that clears its thresholds,
+// best match first.
+func (s *Store) Identify(query []panako.HashedFingerprint) ([]Match, error) {
+	candidates := map[uint32][]panako.HashedFingerprint{}
+
+	for _, fp := range query {
+		postings, err := s.Lookup(fp.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, posting := range postings {
+			candidates[posting.TrackID] = append(candidates[posting.TrackID], panako.HashedFingerprint{
+				Hash:      fp.Hash,
+				Timestamp: float64(posting.Timestamp),
+			})
+		}
+	}
+
+	var matches []Match
+
+	for trackID, reference := range candidates {
+		result := panako.Compare(query, reference)
+		if !result.Matched {
+			continue
+		}
+
+		matches = append(matches, Match{TrackID: trackID, CompareResult: result})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Hits > matches[j].Hits
+	})
+
+	return matches, nil
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Crystalix007/simsalabim/fingerprint/panako"
+)
+
+func TestAddAndLookupRoundTrip(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	fingerprints := []panako.HashedFingerprint{
+		{Hash: 1, Timestamp: 0.1},
+		{Hash: 2, Timestamp: 0.2},
+	}
+
+	if err := s.Add(7, fingerprints, TrackMetadata{ReplayGain: -3.5, ReplayPeak: 0.9}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	postings, err := s.Lookup(1)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if len(postings) != 1 || postings[0].TrackID != 7 {
+		t.Errorf("Lookup(1) = %+v, want one posting for track 7", postings)
+	}
+
+	metadata, err := s.Metadata(7)
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	if metadata.ReplayGain != -3.5 || metadata.ReplayPeak != 0.9 {
+		t.Errorf("Metadata() = %+v, want {-3.5 0.9}", metadata)
+	}
+}
+
+// TestAddCompactsAfterThreshold adds CompactionThreshold tracks, each in its
+// own segment, and checks that compaction folds them into the main index and
+// removes the now-redundant segment files.
+func TestAddCompactsAfterThreshold(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	for track := uint32(0); track < CompactionThreshold; track++ {
+		fp := []panako.HashedFingerprint{{Hash: panako.Hash(track), Timestamp: float64(track)}}
+		if err := s.Add(track, fp, TrackMetadata{}); err != nil {
+			t.Fatalf("Add(%d) error = %v", track, err)
+		}
+	}
+
+	segments, err := filepath.Glob(s.segmentsGlob())
+	if err != nil {
+		t.Fatalf("listing segments: %v", err)
+	}
+	if len(segments) != 0 {
+		t.Errorf("pending segments after compaction = %d, want 0", len(segments))
+	}
+
+	for track := uint32(0); track < CompactionThreshold; track++ {
+		postings, err := s.Lookup(panako.Hash(track))
+		if err != nil {
+			t.Fatalf("Lookup(%d) error = %v", track, err)
+		}
+		if len(postings) != 1 || postings[0].TrackID != track {
+			t.Errorf("Lookup(%d) = %+v, want one posting for track %d", track, postings, track)
+		}
+	}
+}
+
+func TestIdentifyMatchesStoredTrack(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	const spacing = panako.MinDurationSeconds / (panako.MinHits - 1)
+
+	var fingerprints []panako.HashedFingerprint
+	for i := 0; i < panako.MinHits; i++ {
+		fingerprints = append(fingerprints, panako.HashedFingerprint{
+			Hash:      panako.Hash(i),
+			Timestamp: float64(i) * spacing,
+		})
+	}
+
+	if err := s.Add(42, fingerprints, TrackMetadata{}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	matches, err := s.Identify(fingerprints)
+	if err != nil {
+		t.Fatalf("Identify() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].TrackID != 42 {
+		t.Errorf("Identify() = %+v, want a single match for track 42", matches)
+	}
+}